@@ -2,46 +2,346 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/time/rate"
 	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/wknapek/httpservice/scanner"
+	"github.com/wknapek/httpservice/storage"
+)
+
+// avScanner, vtScanner and backend are configured once in main from flags
+// and then shared by every request handler.
+var (
+	avScanner         scanner.Scanner
+	avPrescan         bool
+	vtScanner         *scanner.VirusTotal
+	vtSizeThresholdKB int64
+
+	backend storage.Backend
 )
 
-func SetupLogger(maxSize int, backups int, age int) {
+// draining is set to 1 once a shutdown signal has been received, so
+// in-flight handlers can reject new work while existing requests finish.
+var draining int32
+
+func isDraining() bool {
+	return atomic.LoadInt32(&draining) == 1
+}
 
-	lumberjackLogger := &lumberjack.Logger{
-		Filename:   "./server.log",
+// newLogFormatter builds the text or JSON formatter shared by the app and
+// access loggers, selected via -log-format.
+func newLogFormatter(jsonFormat bool) log.Formatter {
+	if jsonFormat {
+		return &log.JSONFormatter{TimestampFormat: time.RFC1123Z}
+	}
+	textFormatter := new(log.TextFormatter)
+	textFormatter.TimestampFormat = time.RFC1123Z
+	textFormatter.FullTimestamp = true
+	return textFormatter
+}
+
+// SetupLogger points the package-level (app/error) logrus logger at a
+// rotating lumberjack file.
+func SetupLogger(filename string, maxSize int, backups int, age int, jsonFormat bool) {
+	log.SetFormatter(newLogFormatter(jsonFormat))
+	log.SetLevel(log.InfoLevel)
+	log.SetOutput(&lumberjack.Logger{
+		Filename:   filename,
 		MaxSize:    maxSize,
 		MaxBackups: backups,
 		MaxAge:     age,
 		Compress:   true,
+	})
+}
+
+// newAccessLogger builds an independent logrus logger for HTTP access logs,
+// rotated separately from the app/error log.
+func newAccessLogger(filename string, maxSize int, backups int, age int, jsonFormat bool) *log.Logger {
+	logger := log.New()
+	logger.SetFormatter(newLogFormatter(jsonFormat))
+	logger.SetLevel(log.InfoLevel)
+	logger.SetOutput(&lumberjack.Logger{
+		Filename:   filename,
+		MaxSize:    maxSize,
+		MaxBackups: backups,
+		MaxAge:     age,
+		Compress:   true,
+	})
+	return logger
+}
+
+// accessLogMiddleware emits one structured logrus entry per request to
+// logger, capturing status, bytes written, and latency.
+func accessLogMiddleware(logger *log.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r)
+
+			logger.WithFields(log.Fields{
+				"remote":      r.RemoteAddr,
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"status":      ww.Status(),
+				"bytes":       ww.BytesWritten(),
+				"duration_ms": time.Since(start).Milliseconds(),
+				"request_id":  middleware.GetReqID(r.Context()),
+				"user_agent":  r.UserAgent(),
+				"referer":     r.Referer(),
+			}).Info("request")
+		})
 	}
+}
+
+// connLimiter caps the number of requests a single listener serves at
+// once, actively returning 503 once the limit is hit instead of letting
+// excess connections queue in the kernel accept backlog.
+type connLimiter struct {
+	max    int
+	active int32
+}
 
-	// Fork writing into two outputs
-	file, err := os.OpenFile(lumberjackLogger.Filename, os.O_WRONLY|os.O_CREATE, 0755)
+func newConnLimiter(max int) *connLimiter {
+	return &connLimiter{max: max}
+}
+
+func (c *connLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(atomic.AddInt32(&c.active, 1)) > c.max {
+			atomic.AddInt32(&c.active, -1)
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		defer atomic.AddInt32(&c.active, -1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// middlewareConfig bundles the opt-in cross-cutting middlewares shared by
+// both routers.
+type middlewareConfig struct {
+	accessLogger   *log.Logger
+	rateLimiter    *rateLimiterStore
+	maxUploadBytes int64
+	corsOrigins    []string
+	trustedProxies []*net.IPNet
+	apiKey         string
+}
+
+// visitor pairs a per-IP limiter with the time it was last used, so
+// rateLimiterStore can evict IPs that have gone quiet.
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiterStore hands out a token-bucket limiter per client IP,
+// evicting entries that haven't been seen in a while.
+type rateLimiterStore struct {
+	mu       sync.Mutex
+	visitors map[string]*visitor
+	rps      rate.Limit
+	burst    int
+}
+
+// newRateLimiterStore builds a store whose limiters allow
+// requestsPerMinute requests per minute per IP, with a matching burst.
+func newRateLimiterStore(requestsPerMinute int) *rateLimiterStore {
+	store := &rateLimiterStore{
+		visitors: make(map[string]*visitor),
+		rps:      rate.Limit(float64(requestsPerMinute) / 60),
+		burst:    requestsPerMinute,
+	}
+	go store.evictStale()
+	return store
+}
+
+func (s *rateLimiterStore) limiterFor(ip string) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.visitors[ip]
+	if !ok {
+		v = &visitor{limiter: rate.NewLimiter(s.rps, s.burst)}
+		s.visitors[ip] = v
+	}
+	v.lastSeen = time.Now()
+	return v.limiter
+}
+
+func (s *rateLimiterStore) evictStale() {
+	for {
+		time.Sleep(time.Minute)
+		s.mu.Lock()
+		for ip, v := range s.visitors {
+			if time.Since(v.lastSeen) > 3*time.Minute {
+				delete(s.visitors, ip)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// rateLimitMiddleware returns 429 with Retry-After once a client IP
+// exceeds its token bucket.
+func rateLimitMiddleware(store *rateLimiterStore, trustedProxies []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !store.limiterFor(clientIP(r, trustedProxies)).Allow() {
+				w.Header().Set("Retry-After", "60")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the connecting peer's address from r.RemoteAddr. The
+// left-most X-Forwarded-For entry is only honored when that peer is one of
+// trustedProxies; otherwise a client could set an arbitrary X-Forwarded-For
+// header to get a fresh rate-limiter bucket on every request.
+func clientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		fmt.Println("cannot create log file")
-		os.Exit(1)
+		peer = r.RemoteAddr
 	}
 
-	logFormatter := new(log.TextFormatter)
-	logFormatter.TimestampFormat = time.RFC1123Z
-	logFormatter.FullTimestamp = true
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" && isTrustedProxy(peer, trustedProxies) {
+		return strings.TrimSpace(strings.Split(xff, ",")[0])
+	}
+	return peer
+}
 
-	log.SetFormatter(logFormatter)
-	log.SetLevel(log.InfoLevel)
-	log.SetOutput(file)
+// isTrustedProxy reports whether ip falls within any of the trusted CIDRs.
+func isTrustedProxy(ip string, trusted []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range trusted {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies parses a comma-separated list of IPs/CIDRs into
+// IPNets. A bare IP is treated as a /32 (or /128 for IPv6).
+func parseTrustedProxies(csv string) ([]*net.IPNet, error) {
+	if csv == "" {
+		return nil, nil
+	}
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(csv, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+			}
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// apiKeyMiddleware requires a matching "Authorization: Bearer <key>" header.
+// It guards the download/delete-by-key routes, where the key is just the
+// client-supplied upload filename: without this, anyone who knows or
+// guesses a key can read or delete another uploader's file.
+func apiKeyMiddleware(apiKey string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const prefix = "Bearer "
+			auth := r.Header.Get("Authorization")
+			if !strings.HasPrefix(auth, prefix) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			given := strings.TrimPrefix(auth, prefix)
+			if subtle.ConstantTimeCompare([]byte(given), []byte(apiKey)) != 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// maxUploadSizeMiddleware caps the request body at maxBytes using
+// http.MaxBytesReader, so an oversized upload fails fast instead of
+// risking OOM.
+func maxUploadSizeMiddleware(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// corsMiddleware echoes back Origin for allowed origins and answers
+// preflight OPTIONS requests.
+func corsMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[origin] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+
+			origin := r.Header.Get("Origin")
+			if allowed[origin] {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			}
+
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 func main() {
@@ -50,21 +350,120 @@ func main() {
 	age := flag.Int("age", 30, "age is the maximum number of days to retain old log files")
 	certFile := flag.String("cert", "localhost.crt", "path to server certificate")
 	key := flag.String("key", "localhost.key", "path to server certificate key")
+	clamavHost := flag.String("clamav-host", os.Getenv("CLAMAV_HOST"), "clamd address: host:port for TCP, or a /path to a unix socket")
+	clamavPrescan := flag.Bool("clamav-prescan", false, "scan uploads while they are being written instead of after they land on disk")
+	virustotalKey := flag.String("virustotal-key", "", "VirusTotal API key; when set, files above -virustotal-min-size are also checked against VirusTotal's hash database")
+	virustotalMinSizeKB := flag.Int64("virustotal-min-size", 1024, "minimum file size in KB before falling back to VirusTotal")
+	provider := flag.String("provider", "local", "storage backend to use: local, s3, or gcs")
+	localDir := flag.String("local-dir", "./uploads", "root directory for the local storage backend")
+	s3Bucket := flag.String("s3-bucket", "", "bucket name for the s3 storage backend")
+	s3Endpoint := flag.String("s3-endpoint", "", "custom S3 endpoint, e.g. for MinIO; empty uses AWS")
+	s3Region := flag.String("s3-region", "us-east-1", "region for the s3 storage backend")
+	purgeDays := flag.Int("purge-days", 0, "delete stored files older than this many days; 0 disables purging")
+	readTimeout := flag.Duration("read-timeout", 15*time.Second, "maximum duration for reading the entire request")
+	writeTimeout := flag.Duration("write-timeout", 15*time.Second, "maximum duration before timing out writes of the response")
+	idleTimeout := flag.Duration("idle-timeout", 60*time.Second, "maximum amount of time to wait for the next request on a keep-alive connection")
+	readHeaderTimeout := flag.Duration("read-header-timeout", 5*time.Second, "maximum duration for reading request headers")
+	maxClients := flag.Int("max-clients", 128, "maximum number of simultaneous in-flight requests per listener; further requests get a 503 until one finishes (idle keep-alive connections are not counted)")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "time to wait for in-flight requests to finish during shutdown")
+	errorLogFile := flag.String("error-log", "./server.log", "path to the app/error log file")
+	accessLogFile := flag.String("access-log", "./access.log", "path to the HTTP access log file")
+	logFormat := flag.String("log-format", "text", "log output format: json or text")
+	letsEncryptHosts := flag.String("lets-encrypt-hosts", "", "comma-separated hostnames to obtain Let's Encrypt certificates for; enables ACME mode instead of -cert/-key")
+	letsEncryptEmail := flag.String("lets-encrypt-email", "", "contact email registered with Let's Encrypt")
+	letsEncryptCache := flag.String("lets-encrypt-cache", "./.certs", "directory used to cache ACME account and certificate data")
+	rateLimit := flag.Int("rate-limit", 0, "requests per minute allowed per client IP; 0 disables rate limiting")
+	maxUploadSizeKB := flag.Int64("max-upload-size", 0, "maximum request body size in KB; 0 disables the limit")
+	corsDomains := flag.String("cors-domains", "", "comma-separated list of origins allowed to make cross-origin requests; empty disables CORS")
+	trustedProxies := flag.String("trusted-proxies", "", "comma-separated list of IPs/CIDRs allowed to set X-Forwarded-For for rate limiting; empty trusts no one and always keys on the connecting peer")
+	apiKey := flag.String("api-key", "", "if set, requires this value as a Bearer token to download or delete files by key; keys are just client-supplied filenames, so this guards against unauthenticated read/delete of other uploaders' files")
+	flag.Parse()
+
+	jsonFormat := *logFormat == "json"
+	SetupLogger(*errorLogFile, *maxSize, *backups, *age, jsonFormat)
+	accessLogger := newAccessLogger(*accessLogFile, *maxSize, *backups, *age, jsonFormat)
+
+	if *clamavHost != "" {
+		avScanner = scanner.NewClamAV(*clamavHost, 5*time.Second)
+	}
+	avPrescan = *clamavPrescan
+	if *virustotalKey != "" {
+		vtScanner = scanner.NewVirusTotal(*virustotalKey)
+	}
+	vtSizeThresholdKB = *virustotalMinSizeKB
+
+	var err error
+	switch *provider {
+	case "local":
+		backend, err = storage.NewLocal(*localDir)
+	case "s3":
+		backend, err = storage.NewS3(context.Background(), *s3Bucket, *s3Region, *s3Endpoint)
+	case "gcs", "gdrive":
+		backend = storage.NewGCS(*s3Bucket)
+	default:
+		err = fmt.Errorf("unknown -provider %q", *provider)
+	}
+	if err != nil {
+		log.Fatalf("cannot initialize storage backend: %v", err)
+	}
+
+	if *purgeDays > 0 {
+		go purgeExpired(time.Duration(*purgeDays) * 24 * time.Hour)
+	}
+
+	timeouts := serverTimeouts{
+		read:       *readTimeout,
+		write:      *writeTimeout,
+		idle:       *idleTimeout,
+		readHeader: *readHeaderTimeout,
+	}
+
+	var acmeManager *autocert.Manager
+	if *letsEncryptHosts != "" {
+		hosts := strings.Split(*letsEncryptHosts, ",")
+		for i := range hosts {
+			hosts[i] = strings.TrimSpace(hosts[i])
+		}
+		acmeManager = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(hosts...),
+			Cache:      autocert.DirCache(*letsEncryptCache),
+			Email:      *letsEncryptEmail,
+		}
+	}
 
-	SetupLogger(*maxSize, *backups, *age)
+	mw := middlewareConfig{accessLogger: accessLogger}
+	if *rateLimit > 0 {
+		mw.rateLimiter = newRateLimiterStore(*rateLimit)
+	}
+	mw.trustedProxies, err = parseTrustedProxies(*trustedProxies)
+	if err != nil {
+		log.Fatalf("cannot parse -trusted-proxies: %v", err)
+	}
+	if *maxUploadSizeKB > 0 {
+		mw.maxUploadBytes = *maxUploadSizeKB * 1024
+	}
+	if *corsDomains != "" {
+		for _, origin := range strings.Split(*corsDomains, ",") {
+			mw.corsOrigins = append(mw.corsOrigins, strings.TrimSpace(origin))
+		}
+	}
+	mw.apiKey = *apiKey
 
 	httpServerExitDone := &sync.WaitGroup{}
 
 	httpServerExitDone.Add(1)
-	server := startHttpServer(httpServerExitDone)
+	server := startHttpServer(httpServerExitDone, timeouts, *maxClients, mw, acmeManager)
 	httpServerExitDone.Add(1)
-	serverSSL := startHttpsServer(httpServerExitDone, *certFile, *key)
+	serverSSL := startHttpsServer(httpServerExitDone, *certFile, *key, timeouts, *maxClients, mw, acmeManager)
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
 
-	ctx, cancel := context.WithCancel(context.Background())
+	atomic.StoreInt32(&draining, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
 	defer cancel()
 	if err := server.Shutdown(ctx); err != nil {
 		panic(err)
@@ -77,22 +476,73 @@ func main() {
 	fmt.Println("server ending work")
 }
 
-func startHttpsServer(wg *sync.WaitGroup, certFile string, key string) *http.Server {
+// serverTimeouts groups the http.Server timeout knobs shared by both the
+// plain and TLS listeners.
+type serverTimeouts struct {
+	read       time.Duration
+	write      time.Duration
+	idle       time.Duration
+	readHeader time.Duration
+}
+
+func startHttpsServer(wg *sync.WaitGroup, certFile string, key string, timeouts serverTimeouts, maxClients int, mw middlewareConfig, acmeManager *autocert.Manager) *http.Server {
 	r := chi.NewRouter()
-	srvSSL := &http.Server{Addr: ":8085", Handler: r}
+	srvSSL := &http.Server{
+		Addr:              ":8085",
+		Handler:           r,
+		ReadTimeout:       timeouts.read,
+		WriteTimeout:      timeouts.write,
+		IdleTimeout:       timeouts.idle,
+		ReadHeaderTimeout: timeouts.readHeader,
+	}
+	if acmeManager != nil {
+		srvSSL.Addr = ":443"
+		srvSSL.TLSConfig = acmeManager.TLSConfig()
+	}
 
-	r.Use(middleware.Logger)
+	r.Use(newConnLimiter(maxClients).middleware)
+	r.Use(middleware.RequestID)
+	r.Use(accessLogMiddleware(mw.accessLogger))
+	if mw.rateLimiter != nil {
+		r.Use(rateLimitMiddleware(mw.rateLimiter, mw.trustedProxies))
+	}
+	if mw.maxUploadBytes > 0 {
+		r.Use(maxUploadSizeMiddleware(mw.maxUploadBytes))
+	}
+	if len(mw.corsOrigins) > 0 {
+		r.Use(corsMiddleware(mw.corsOrigins))
+	}
 
 	staticFiles := http.FileServer(http.Dir("./public"))
 
 	r.Get("/hello/{status}", hello)
 	r.Post("/upload", uploadFile)
+	r.Group(func(r chi.Router) {
+		if mw.apiKey != "" {
+			r.Use(apiKeyMiddleware(mw.apiKey))
+		}
+		r.Get("/download/{key}", downloadFile)
+		r.Delete("/files/{key}", deleteFile)
+	})
 	r.Handle("/", staticFiles)
 
 	go func() {
 		defer wg.Done()
 
-		if err := srvSSL.ListenAndServeTLS(certFile, key); err != http.ErrServerClosed {
+		ln, err := net.Listen("tcp", srvSSL.Addr)
+		if err != nil {
+			log.Fatalf("listen %s: %v", srvSSL.Addr, err)
+		}
+
+		// ServeTLS calls tls.LoadX509KeyPair whenever either argument is
+		// non-empty, even if TLSConfig.GetCertificate is already set, so
+		// the ACME-provided TLSConfig above must get empty strings here
+		// or it tries (and fails) to load certFile/key from disk.
+		tlsCertFile, tlsKey := certFile, key
+		if acmeManager != nil {
+			tlsCertFile, tlsKey = "", ""
+		}
+		if err := srvSSL.ServeTLS(ln, tlsCertFile, tlsKey); err != http.ErrServerClosed {
 			log.Fatalf("ListenAndServe(): %v", err)
 		}
 	}()
@@ -100,22 +550,59 @@ func startHttpsServer(wg *sync.WaitGroup, certFile string, key string) *http.Ser
 	return srvSSL
 }
 
-func startHttpServer(wg *sync.WaitGroup) *http.Server {
+func startHttpServer(wg *sync.WaitGroup, timeouts serverTimeouts, maxClients int, mw middlewareConfig, acmeManager *autocert.Manager) *http.Server {
 	r := chi.NewRouter()
-	srvNoSSL := &http.Server{Addr: ":8080", Handler: r}
+	srvNoSSL := &http.Server{
+		Addr:              ":8080",
+		Handler:           r,
+		ReadTimeout:       timeouts.read,
+		WriteTimeout:      timeouts.write,
+		IdleTimeout:       timeouts.idle,
+		ReadHeaderTimeout: timeouts.readHeader,
+	}
 
-	r.Use(middleware.Logger)
+	r.Use(newConnLimiter(maxClients).middleware)
+	r.Use(middleware.RequestID)
+	r.Use(accessLogMiddleware(mw.accessLogger))
+	if mw.rateLimiter != nil {
+		r.Use(rateLimitMiddleware(mw.rateLimiter, mw.trustedProxies))
+	}
+	if mw.maxUploadBytes > 0 {
+		r.Use(maxUploadSizeMiddleware(mw.maxUploadBytes))
+	}
+	if len(mw.corsOrigins) > 0 {
+		r.Use(corsMiddleware(mw.corsOrigins))
+	}
 
 	staticFiles := http.FileServer(http.Dir("./public"))
 
 	r.Get("/hello/{status}", hello)
 	r.Post("/upload", uploadFile)
+	r.Group(func(r chi.Router) {
+		if mw.apiKey != "" {
+			r.Use(apiKeyMiddleware(mw.apiKey))
+		}
+		r.Get("/download/{key}", downloadFile)
+		r.Delete("/files/{key}", deleteFile)
+	})
 	r.Handle("/", staticFiles)
 
+	if acmeManager != nil {
+		// :80 only needs to answer the ACME HTTP-01 challenge and redirect
+		// everything else to HTTPS; the real routes live on the TLS server.
+		srvNoSSL.Addr = ":80"
+		srvNoSSL.Handler = acmeManager.HTTPHandler(nil)
+	}
+
 	go func() {
 		defer wg.Done()
 
-		if err := srvNoSSL.ListenAndServe(); err != http.ErrServerClosed {
+		ln, err := net.Listen("tcp", srvNoSSL.Addr)
+		if err != nil {
+			log.Fatalf("listen %s: %v", srvNoSSL.Addr, err)
+		}
+
+		if err := srvNoSSL.Serve(ln); err != http.ErrServerClosed {
 			log.Fatalf("ListenAndServe(): %v", err)
 		}
 	}()
@@ -124,30 +611,32 @@ func startHttpServer(wg *sync.WaitGroup) *http.Server {
 }
 
 func hello(w http.ResponseWriter, r *http.Request) {
+	reqLog := log.WithField("request_id", middleware.GetReqID(r.Context()))
+
 	status := chi.URLParam(r, "status")
 	if status == "statusnotnound" {
-		log.Info("status not found")
+		reqLog.Info("status not found")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 	if status == "statusbadrequest" {
-		log.Info("status bad request")
+		reqLog.Info("status bad request")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
 	if status == "statusok" {
-		log.Info("status OK")
+		reqLog.Info("status OK")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 	if status == "statusinternalservererror" {
-		log.Info("status internal server error")
+		reqLog.Info("status internal server error")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 	if status == "statusonauthoritativeinformation" {
-		log.Info("status non-authoritative information")
+		reqLog.Info("status non-authoritative information")
 		w.WriteHeader(http.StatusNonAuthoritativeInfo)
 		return
 	}
@@ -155,28 +644,199 @@ func hello(w http.ResponseWriter, r *http.Request) {
 }
 
 func uploadFile(w http.ResponseWriter, r *http.Request) {
-	r.ParseMultipartForm(32 << 20)
+	if isDraining() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+	reqLog := log.WithField("request_id", reqID)
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		reqLog.Infof("cannot parse multipart form : %s", err.Error())
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
 
 	file, handler, err := r.FormFile("file")
 	if err != nil {
-		log.Info("cannot read file : %s", err.Error())
+		reqLog.Infof("cannot read file : %s", err.Error())
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 	defer file.Close()
-	fileBytes, err := io.ReadAll(file)
+
+	// Stage the upload in a temp file so it can be scanned before it is
+	// handed to the storage backend.
+	staged, err := os.CreateTemp("", "upload-*")
 	if err != nil {
-		log.Info("cannot read file : %s", err.Error())
+		reqLog.Infof("cannot create file : %s", err.Error())
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	fileSave, err := os.Create(handler.Filename)
+	stagedPath := staged.Name()
+	defer os.Remove(stagedPath)
+
+	if avPrescan && avScanner != nil {
+		clean, signature, err := avScanner.Scan(r.Context(), io.TeeReader(file, staged))
+		staged.Close()
+		if err != nil {
+			reqLog.WithError(err).Error("clamav prescan failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !clean {
+			logScanVerdict(reqID, handler.Filename, handler.Size, "infected", signature)
+			writeInfectedResponse(w, signature)
+			return
+		}
+		logScanVerdict(reqID, handler.Filename, handler.Size, "clean", "")
+	} else {
+		if _, err := io.Copy(staged, file); err != nil {
+			staged.Close()
+			reqLog.Infof("cannot create file : %s", err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		staged.Close()
+
+		if avScanner != nil {
+			clean, signature, err := scanStagedFile(r.Context(), avScanner, stagedPath)
+			if err != nil {
+				reqLog.WithError(err).Error("clamav scan failed")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if !clean {
+				logScanVerdict(reqID, handler.Filename, handler.Size, "infected", signature)
+				writeInfectedResponse(w, signature)
+				return
+			}
+			logScanVerdict(reqID, handler.Filename, handler.Size, "clean", "")
+		}
+	}
+
+	if vtScanner != nil && handler.Size > vtSizeThresholdKB*1024 {
+		clean, signature, err := scanStagedFile(r.Context(), vtScanner, stagedPath)
+		if err != nil {
+			reqLog.WithError(err).Error("virustotal lookup failed")
+		} else if !clean {
+			logScanVerdict(reqID, handler.Filename, handler.Size, "infected", signature)
+			writeInfectedResponse(w, signature)
+			return
+		} else {
+			logScanVerdict(reqID, handler.Filename, handler.Size, "clean", "")
+		}
+	}
+
+	staged, err = os.Open(stagedPath)
 	if err != nil {
-		log.Info("cannot create file : %s", err.Error())
+		reqLog.WithError(err).Error("cannot reopen staged file")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	fileSave.Write(fileBytes)
-	log.Info("file %s saved", handler.Filename)
+	defer staged.Close()
+
+	meta := storage.Metadata{ContentType: handler.Header.Get("Content-Type"), Size: handler.Size}
+	if err := backend.Put(r.Context(), handler.Filename, staged, meta); err != nil {
+		reqLog.WithError(err).Error("cannot save file to storage backend")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	reqLog.Infof("file %s saved", handler.Filename)
 	w.WriteHeader(http.StatusOK)
 }
+
+// scanStagedFile reopens the staged upload at path and runs s over it.
+func scanStagedFile(ctx context.Context, s scanner.Scanner, path string) (clean bool, signature string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, "", fmt.Errorf("open staged file: %w", err)
+	}
+	defer f.Close()
+	return s.Scan(ctx, f)
+}
+
+func downloadFile(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+	reqLog := log.WithField("request_id", middleware.GetReqID(r.Context()))
+
+	rc, meta, err := backend.Get(r.Context(), key)
+	if err == storage.ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		reqLog.WithError(err).Error("cannot read file from storage backend")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer rc.Close()
+
+	if meta.ContentType != "" {
+		w.Header().Set("Content-Type", meta.ContentType)
+	}
+	io.Copy(w, rc)
+}
+
+func deleteFile(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+
+	if err := backend.Delete(r.Context(), key); err != nil {
+		log.WithField("request_id", middleware.GetReqID(r.Context())).WithError(err).Error("cannot delete file from storage backend")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// purgeExpired periodically deletes stored files whose last modification is
+// older than maxAge.
+func purgeExpired(maxAge time.Duration) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		keys, err := backend.List(context.Background())
+		if err != nil {
+			log.WithError(err).Error("purge: cannot list stored files")
+		} else {
+			for _, key := range keys {
+				meta, err := backend.Head(context.Background(), key)
+				if err != nil {
+					log.WithError(err).WithField("file", key).Error("purge: cannot stat file")
+					continue
+				}
+				if time.Since(meta.ModTime) <= maxAge {
+					continue
+				}
+				if err := backend.Delete(context.Background(), key); err != nil {
+					log.WithError(err).WithField("file", key).Error("purge: cannot delete expired file")
+					continue
+				}
+				log.WithField("file", key).Info("purge: deleted expired file")
+			}
+		}
+		<-ticker.C
+	}
+}
+
+func logScanVerdict(requestID string, file string, size int64, verdict string, signature string) {
+	log.WithFields(log.Fields{
+		"request_id": requestID,
+		"file":       file,
+		"size":       size,
+		"verdict":    verdict,
+		"signature":  signature,
+	}).Info("scan verdict")
+}
+
+func writeInfectedResponse(w http.ResponseWriter, signature string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":     "file is infected",
+		"signature": signature,
+	})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	cases := []struct {
+		csv     string
+		want    []string
+		wantErr bool
+	}{
+		{csv: "", want: nil},
+		{csv: "10.0.0.1", want: []string{"10.0.0.1/32"}},
+		{csv: "10.0.0.0/8", want: []string{"10.0.0.0/8"}},
+		{csv: "10.0.0.1, 192.168.1.0/24", want: []string{"10.0.0.1/32", "192.168.1.0/24"}},
+		{csv: "::1", want: []string{"::1/128"}},
+		{csv: "not-an-ip", wantErr: true},
+		{csv: "10.0.0.0/abc", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		got, err := parseTrustedProxies(tc.csv)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseTrustedProxies(%q): want error, got nil", tc.csv)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseTrustedProxies(%q): unexpected error: %v", tc.csv, err)
+			continue
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("parseTrustedProxies(%q) = %v, want %v", tc.csv, got, tc.want)
+			continue
+		}
+		for i, network := range got {
+			if network.String() != tc.want[i] {
+				t.Errorf("parseTrustedProxies(%q)[%d] = %q, want %q", tc.csv, i, network.String(), tc.want[i])
+			}
+		}
+	}
+}
+
+func TestIsTrustedProxy(t *testing.T) {
+	trusted, err := parseTrustedProxies("10.0.0.0/8,192.168.1.1")
+	if err != nil {
+		t.Fatalf("parseTrustedProxies: %v", err)
+	}
+
+	cases := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "10.1.2.3", want: true},
+		{ip: "192.168.1.1", want: true},
+		{ip: "192.168.1.2", want: false},
+		{ip: "203.0.113.1", want: false},
+		{ip: "not-an-ip", want: false},
+	}
+
+	for _, tc := range cases {
+		if got := isTrustedProxy(tc.ip, trusted); got != tc.want {
+			t.Errorf("isTrustedProxy(%q) = %v, want %v", tc.ip, got, tc.want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	var noneTrusted []*net.IPNet
+	trusted, err := parseTrustedProxies("10.0.0.1")
+	if err != nil {
+		t.Fatalf("parseTrustedProxies: %v", err)
+	}
+
+	cases := []struct {
+		name           string
+		remoteAddr     string
+		xff            string
+		trustedProxies []*net.IPNet
+		want           string
+	}{
+		{
+			name:       "no XFF uses remote addr",
+			remoteAddr: "203.0.113.5:1234",
+			want:       "203.0.113.5",
+		},
+		{
+			name:           "XFF from untrusted peer is ignored",
+			remoteAddr:     "203.0.113.5:1234",
+			xff:            "1.2.3.4",
+			trustedProxies: noneTrusted,
+			want:           "203.0.113.5",
+		},
+		{
+			name:           "XFF from trusted peer is honored",
+			remoteAddr:     "10.0.0.1:1234",
+			xff:            "1.2.3.4, 10.0.0.1",
+			trustedProxies: trusted,
+			want:           "1.2.3.4",
+		},
+	}
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tc.remoteAddr
+		if tc.xff != "" {
+			r.Header.Set("X-Forwarded-For", tc.xff)
+		}
+		if got := clientIP(r, tc.trustedProxies); got != tc.want {
+			t.Errorf("%s: clientIP() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// chunkSize is the maximum size of a single INSTREAM chunk sent to clamd.
+const chunkSize = 8192
+
+// ClamAV scans files by streaming them to a clamd daemon using the INSTREAM
+// command, over either a TCP or a Unix domain socket.
+type ClamAV struct {
+	network string // "tcp" or "unix"
+	address string
+	dialer  net.Dialer
+}
+
+// NewClamAV builds a ClamAV client for host. A host starting with "/" is
+// treated as a path to a Unix socket; anything else is dialed as TCP
+// (host:port).
+func NewClamAV(host string, dialTimeout time.Duration) *ClamAV {
+	c := &ClamAV{
+		network: "tcp",
+		address: host,
+		dialer:  net.Dialer{Timeout: dialTimeout},
+	}
+	if strings.HasPrefix(host, "/") {
+		c.network = "unix"
+	}
+	return c
+}
+
+// Scan streams r to clamd via INSTREAM and reports the verdict.
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) (clean bool, signature string, err error) {
+	conn, err := c.dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return false, "", fmt.Errorf("clamav: dial %s %s: %w", c.network, c.address, err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return false, "", fmt.Errorf("clamav: write command: %w", err)
+	}
+
+	buf := make([]byte, chunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return false, "", fmt.Errorf("clamav: write chunk size: %w", err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return false, "", fmt.Errorf("clamav: write chunk: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return false, "", fmt.Errorf("clamav: read file: %w", readErr)
+		}
+	}
+	// Zero-length chunk terminates the stream.
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return false, "", fmt.Errorf("clamav: write terminator: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return false, "", fmt.Errorf("clamav: read reply: %w", err)
+	}
+	reply = strings.TrimRight(reply, "\x00\r\n")
+
+	switch {
+	case strings.HasSuffix(reply, "OK"):
+		return true, "", nil
+	case strings.Contains(reply, "FOUND"):
+		sig := strings.TrimSuffix(strings.TrimPrefix(reply, "stream: "), " FOUND")
+		return false, sig, nil
+	default:
+		return false, "", fmt.Errorf("clamav: unexpected reply %q", reply)
+	}
+}
@@ -0,0 +1,116 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd accepts a single INSTREAM session, collects the streamed bytes,
+// and replies with reply.
+func fakeClamd(t *testing.T, reply string) (addr string, received chan []byte) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received = make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		br := bufio.NewReader(conn)
+		cmd, err := br.ReadString(0)
+		if err != nil || cmd != "zINSTREAM\x00" {
+			return
+		}
+
+		var body []byte
+		for {
+			size := make([]byte, 4)
+			if _, err := io.ReadFull(br, size); err != nil {
+				return
+			}
+			n := binary.BigEndian.Uint32(size)
+			if n == 0 {
+				break
+			}
+			chunk := make([]byte, n)
+			if _, err := io.ReadFull(br, chunk); err != nil {
+				return
+			}
+			body = append(body, chunk...)
+		}
+		received <- body
+
+		conn.Write([]byte(reply))
+	}()
+
+	return ln.Addr().String(), received
+}
+
+func TestClamAVScanClean(t *testing.T) {
+	addr, received := fakeClamd(t, "stream: OK\x00")
+	c := NewClamAV(addr, time.Second)
+
+	clean, sig, err := c.Scan(context.Background(), strings.NewReader("hello world"))
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if !clean || sig != "" {
+		t.Fatalf("Scan() = (%v, %q), want (true, \"\")", clean, sig)
+	}
+	if got := string(<-received); got != "hello world" {
+		t.Errorf("clamd received %q, want %q", got, "hello world")
+	}
+}
+
+func TestClamAVScanInfected(t *testing.T) {
+	addr, _ := fakeClamd(t, "stream: Eicar-Test-Signature FOUND\x00")
+	c := NewClamAV(addr, time.Second)
+
+	clean, sig, err := c.Scan(context.Background(), strings.NewReader("EICAR"))
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if clean || sig != "Eicar-Test-Signature" {
+		t.Fatalf("Scan() = (%v, %q), want (false, %q)", clean, sig, "Eicar-Test-Signature")
+	}
+}
+
+func TestClamAVScanUnexpectedReply(t *testing.T) {
+	addr, _ := fakeClamd(t, "garbage\x00")
+	c := NewClamAV(addr, time.Second)
+
+	if _, _, err := c.Scan(context.Background(), strings.NewReader("data")); err == nil {
+		t.Fatal("Scan(): want error on unexpected reply, got nil")
+	}
+}
+
+func TestClamAVScanChunksLargeInput(t *testing.T) {
+	addr, received := fakeClamd(t, "stream: OK\x00")
+	c := NewClamAV(addr, time.Second)
+
+	input := strings.Repeat("a", chunkSize*2+100)
+	clean, _, err := c.Scan(context.Background(), strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if !clean {
+		t.Fatal("Scan() = clean=false, want true")
+	}
+	if got := string(<-received); got != input {
+		t.Errorf("clamd received %d bytes, want %d", len(got), len(input))
+	}
+}
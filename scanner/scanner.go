@@ -0,0 +1,14 @@
+// Package scanner provides virus-scanning of uploaded files.
+package scanner
+
+import (
+	"context"
+	"io"
+)
+
+// Scanner scans the bytes read from r and reports whether they are clean.
+// When clean is false, signature carries the name of the matching virus
+// signature, if one was returned by the underlying engine.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (clean bool, signature string, err error)
+}
@@ -0,0 +1,84 @@
+package scanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const virusTotalFileReportURL = "https://www.virustotal.com/api/v3/files/%s"
+
+// VirusTotal scans files by hashing them and looking up the hash in
+// VirusTotal's file report API, rather than uploading the file content.
+type VirusTotal struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewVirusTotal builds a VirusTotal client authenticated with apiKey.
+func NewVirusTotal(apiKey string) *VirusTotal {
+	return &VirusTotal{apiKey: apiKey, client: http.DefaultClient}
+}
+
+type virusTotalResponse struct {
+	Data struct {
+		Attributes struct {
+			LastAnalysisStats struct {
+				Malicious int `json:"malicious"`
+			} `json:"last_analysis_stats"`
+			LastAnalysisResults map[string]struct {
+				Category string `json:"category"`
+				Result   string `json:"result"`
+			} `json:"last_analysis_results"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// Scan hashes r and looks the hash up in VirusTotal. A 404 (hash unknown to
+// VirusTotal) is treated as clean, since VirusTotal only knows about files
+// it has seen before.
+func (v *VirusTotal) Scan(ctx context.Context, r io.Reader) (clean bool, signature string, err error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return false, "", fmt.Errorf("virustotal: hash file: %w", err)
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(virusTotalFileReportURL, hash), nil)
+	if err != nil {
+		return false, "", fmt.Errorf("virustotal: build request: %w", err)
+	}
+	req.Header.Set("x-apikey", v.apiKey)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return false, "", fmt.Errorf("virustotal: request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return true, "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, "", fmt.Errorf("virustotal: unexpected status %d", resp.StatusCode)
+	}
+
+	var report virusTotalResponse
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return false, "", fmt.Errorf("virustotal: decode response: %w", err)
+	}
+
+	if report.Data.Attributes.LastAnalysisStats.Malicious == 0 {
+		return true, "", nil
+	}
+	for engine, result := range report.Data.Attributes.LastAnalysisResults {
+		if result.Category == "malicious" && result.Result != "" {
+			return false, fmt.Sprintf("%s:%s", engine, result.Result), nil
+		}
+	}
+	return false, "malicious", nil
+}
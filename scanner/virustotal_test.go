@@ -0,0 +1,91 @@
+package scanner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// rewriteTransport redirects every request to target, so tests can point
+// VirusTotal at an httptest.Server instead of the real API.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTestVirusTotal(t *testing.T, status int, body string) *VirusTotal {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("x-apikey"); got != "test-key" {
+			t.Errorf("x-apikey header = %q, want %q", got, "test-key")
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("parse test server URL: %v", err)
+	}
+
+	vt := NewVirusTotal("test-key")
+	vt.client = &http.Client{Transport: &rewriteTransport{target: target}}
+	return vt
+}
+
+func TestVirusTotalScanNotFoundIsClean(t *testing.T) {
+	vt := newTestVirusTotal(t, http.StatusNotFound, "")
+
+	clean, sig, err := vt.Scan(context.Background(), strings.NewReader("some file"))
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if !clean || sig != "" {
+		t.Fatalf("Scan() = (%v, %q), want (true, \"\")", clean, sig)
+	}
+}
+
+func TestVirusTotalScanCleanReport(t *testing.T) {
+	body := `{"data":{"attributes":{"last_analysis_stats":{"malicious":0}}}}`
+	vt := newTestVirusTotal(t, http.StatusOK, body)
+
+	clean, sig, err := vt.Scan(context.Background(), strings.NewReader("some file"))
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if !clean || sig != "" {
+		t.Fatalf("Scan() = (%v, %q), want (true, \"\")", clean, sig)
+	}
+}
+
+func TestVirusTotalScanMaliciousReport(t *testing.T) {
+	body := `{"data":{"attributes":{"last_analysis_stats":{"malicious":1},
+		"last_analysis_results":{"SomeEngine":{"category":"malicious","result":"Trojan.Generic"}}}}}`
+	vt := newTestVirusTotal(t, http.StatusOK, body)
+
+	clean, sig, err := vt.Scan(context.Background(), strings.NewReader("some file"))
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if clean || sig != "SomeEngine:Trojan.Generic" {
+		t.Fatalf("Scan() = (%v, %q), want (false, %q)", clean, sig, "SomeEngine:Trojan.Generic")
+	}
+}
+
+func TestVirusTotalScanUnexpectedStatus(t *testing.T) {
+	vt := newTestVirusTotal(t, http.StatusInternalServerError, "")
+
+	if _, _, err := vt.Scan(context.Background(), strings.NewReader("some file")); err == nil {
+		t.Fatal("Scan(): want error on unexpected status, got nil")
+	}
+}
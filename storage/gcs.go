@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// errNotImplemented is returned by every GCS method. GCS (and Google
+// Drive, which would follow the same shape) is stubbed out pending a
+// decision on which Google API and auth flow to support.
+var errNotImplemented = errors.New("storage: gcs backend not implemented")
+
+// GCS is a placeholder Backend for Google Cloud Storage / Google Drive.
+// NewGCS is kept so -provider=gcs resolves to a concrete (if non-functional)
+// backend instead of a nil pointer.
+type GCS struct {
+	bucket string
+}
+
+// NewGCS returns a stub GCS backend for bucket.
+func NewGCS(bucket string) *GCS {
+	return &GCS{bucket: bucket}
+}
+
+func (g *GCS) Put(ctx context.Context, key string, r io.Reader, meta Metadata) error {
+	return errNotImplemented
+}
+
+func (g *GCS) Get(ctx context.Context, key string) (io.ReadCloser, Metadata, error) {
+	return nil, Metadata{}, errNotImplemented
+}
+
+func (g *GCS) Head(ctx context.Context, key string) (Metadata, error) {
+	return Metadata{}, errNotImplemented
+}
+
+func (g *GCS) Delete(ctx context.Context, key string) error {
+	return errNotImplemented
+}
+
+func (g *GCS) List(ctx context.Context) ([]string, error) {
+	return nil, errNotImplemented
+}
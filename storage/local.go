@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// metaSuffix marks the sidecar file that carries metadata (currently just
+// ContentType) for the object stored alongside it.
+const metaSuffix = ".meta.json"
+
+// Local stores files on disk rooted at dir. Keys are resolved relative to
+// dir with path.Clean-style traversal prevention, so a crafted key such as
+// "../../etc/passwd" cannot escape root.
+type Local struct {
+	root string
+}
+
+// NewLocal builds a Local backend rooted at dir, creating it if needed.
+func NewLocal(dir string) (*Local, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("storage: resolve root %q: %w", dir, err)
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return nil, fmt.Errorf("storage: create root %q: %w", abs, err)
+	}
+	return &Local{root: abs}, nil
+}
+
+// resolve maps key to an absolute path guaranteed to stay under root.
+func (l *Local) resolve(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	full := filepath.Join(l.root, cleaned)
+	if full != l.root && !strings.HasPrefix(full, l.root+string(os.PathSeparator)) {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	return full, nil
+}
+
+// sidecarMeta is the on-disk shape of a key's metadata file.
+type sidecarMeta struct {
+	ContentType string `json:"content_type"`
+}
+
+func (l *Local) writeSidecarMeta(full string, meta Metadata) error {
+	data, err := json.Marshal(sidecarMeta{ContentType: meta.ContentType})
+	if err != nil {
+		return fmt.Errorf("storage: encode metadata: %w", err)
+	}
+	if err := os.WriteFile(full+metaSuffix, data, 0o644); err != nil {
+		return fmt.Errorf("storage: write metadata: %w", err)
+	}
+	return nil
+}
+
+func (l *Local) readSidecarMeta(full string) sidecarMeta {
+	data, err := os.ReadFile(full + metaSuffix)
+	if err != nil {
+		return sidecarMeta{}
+	}
+	var meta sidecarMeta
+	json.Unmarshal(data, &meta)
+	return meta
+}
+
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, meta Metadata) error {
+	full, err := l.resolve(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return fmt.Errorf("storage: create parent dir for %q: %w", key, err)
+	}
+	f, err := os.Create(full)
+	if err != nil {
+		return fmt.Errorf("storage: create %q: %w", key, err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("storage: write %q: %w", key, err)
+	}
+	if err := l.writeSidecarMeta(full, meta); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, Metadata, error) {
+	full, err := l.resolve(key)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	f, err := os.Open(full)
+	if os.IsNotExist(err) {
+		return nil, Metadata{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Metadata{}, fmt.Errorf("storage: open %q: %w", key, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, Metadata{}, fmt.Errorf("storage: stat %q: %w", key, err)
+	}
+	sidecar := l.readSidecarMeta(full)
+	return f, Metadata{ContentType: sidecar.ContentType, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (l *Local) Head(ctx context.Context, key string) (Metadata, error) {
+	full, err := l.resolve(key)
+	if err != nil {
+		return Metadata{}, err
+	}
+	info, err := os.Stat(full)
+	if os.IsNotExist(err) {
+		return Metadata{}, ErrNotFound
+	}
+	if err != nil {
+		return Metadata{}, fmt.Errorf("storage: stat %q: %w", key, err)
+	}
+	sidecar := l.readSidecarMeta(full)
+	return Metadata{ContentType: sidecar.ContentType, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	full, err := l.resolve(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(full); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("storage: delete %q: %w", key, err)
+	}
+	if err := os.Remove(full + metaSuffix); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("storage: delete metadata for %q: %w", key, err)
+	}
+	return nil
+}
+
+func (l *Local) List(ctx context.Context) ([]string, error) {
+	var keys []string
+	err := filepath.Walk(l.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasSuffix(path, metaSuffix) {
+			return nil
+		}
+		rel, err := filepath.Rel(l.root, path)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storage: list %q: %w", l.root, err)
+	}
+	return keys, nil
+}
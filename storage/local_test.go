@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLocalResolve(t *testing.T) {
+	l, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+
+	keys := []string{
+		"report.pdf",
+		"sub/dir/report.pdf",
+		"../../etc/passwd",
+		"..",
+		"a/../../b",
+		"/etc/passwd",
+		"../../../../../../etc/passwd",
+	}
+
+	for _, key := range keys {
+		full, err := l.resolve(key)
+		if err != nil {
+			// Rejecting the key outright is a safe outcome too.
+			continue
+		}
+		if full != l.root && !strings.HasPrefix(full, l.root+"/") {
+			t.Errorf("resolve(%q) = %q, escaped root %q", key, full, l.root)
+		}
+	}
+}
+
+func TestLocalResolveStaysWithinRoot(t *testing.T) {
+	l, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+
+	full, err := l.resolve("a/b/c.txt")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if !strings.HasPrefix(full, l.root+"/") {
+		t.Fatalf("resolve(%q) = %q, want prefix %q", "a/b/c.txt", full, l.root+"/")
+	}
+}
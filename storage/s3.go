@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3 stores files in an S3-compatible bucket. Setting endpoint lets it
+// target MinIO or any other S3-compatible service instead of AWS.
+type S3 struct {
+	bucket   string
+	client   *s3.Client
+	uploader *manager.Uploader
+}
+
+// NewS3 builds an S3 backend for bucket in region, optionally pointed at a
+// custom endpoint (e.g. a MinIO instance) instead of AWS.
+func NewS3(ctx context.Context, bucket, region, endpoint string) (*S3, error) {
+	optFns := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	cfg, err := config.LoadDefaultConfig(ctx, optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("storage: load aws config: %w", err)
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+
+	return &S3{
+		bucket:   bucket,
+		client:   client,
+		uploader: manager.NewUploader(client),
+	}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, meta Metadata) error {
+	_, err := s.uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        r,
+		ContentType: aws.String(meta.ContentType),
+	})
+	if err != nil {
+		return fmt.Errorf("storage: s3 put %q: %w", key, err)
+	}
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, Metadata, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if isS3NotFound(err) {
+		return nil, Metadata{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Metadata{}, fmt.Errorf("storage: s3 get %q: %w", key, err)
+	}
+	meta := Metadata{ContentType: aws.ToString(out.ContentType)}
+	if out.ContentLength != nil {
+		meta.Size = *out.ContentLength
+	}
+	if out.LastModified != nil {
+		meta.ModTime = *out.LastModified
+	}
+	return out.Body, meta, nil
+}
+
+func (s *S3) Head(ctx context.Context, key string) (Metadata, error) {
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if isS3NotFound(err) {
+		return Metadata{}, ErrNotFound
+	}
+	if err != nil {
+		return Metadata{}, fmt.Errorf("storage: s3 head %q: %w", key, err)
+	}
+	meta := Metadata{ContentType: aws.ToString(out.ContentType)}
+	if out.ContentLength != nil {
+		meta.Size = *out.ContentLength
+	}
+	if out.LastModified != nil {
+		meta.ModTime = *out.LastModified
+	}
+	return meta, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("storage: s3 delete %q: %w", key, err)
+	}
+	return nil
+}
+
+func (s *S3) List(ctx context.Context) ([]string, error) {
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("storage: s3 list %q: %w", s.bucket, err)
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+	}
+	return keys, nil
+}
+
+func isS3NotFound(err error) bool {
+	var noSuchKey *types.NoSuchKey
+	var notFound *types.NotFound
+	return errors.As(err, &noSuchKey) || errors.As(err, &notFound)
+}
@@ -0,0 +1,31 @@
+// Package storage abstracts where uploaded files ultimately live, so the
+// HTTP handlers in main don't care whether a file ends up on local disk,
+// in S3, or in some other object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned by Get and Head when key does not exist.
+var ErrNotFound = errors.New("storage: key not found")
+
+// Metadata describes a stored object.
+type Metadata struct {
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// Backend persists uploaded files under a string key and lists or expires
+// them later. Implementations must be safe for concurrent use.
+type Backend interface {
+	Put(ctx context.Context, key string, r io.Reader, meta Metadata) error
+	Get(ctx context.Context, key string) (io.ReadCloser, Metadata, error)
+	Head(ctx context.Context, key string) (Metadata, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context) ([]string, error)
+}